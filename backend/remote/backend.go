@@ -0,0 +1,356 @@
+// Package remote implements the Remote backend, which stores state in,
+// and runs operations against, Terraform Cloud/Enterprise (TFE).
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/terraform/backend"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/states/statemgr"
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/hashicorp/terraform/svchost/disco"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/mitchellh/cli"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// defaultHostname is the hostname used when no explicit hostname is
+// configured, i.e. Terraform Cloud itself.
+const defaultHostname = "app.terraform.io"
+
+// Remote is a backend implementation that stores state and runs
+// operations against Terraform Cloud/Enterprise (TFE).
+type Remote struct {
+	// CLI and Colorize control the CLI output.
+	CLI      cli.Ui
+	Colorize func(string) string
+
+	// ShowDiagnostics prints diagnostics for a wrapping backend.
+	ShowDiagnostics func(vals ...interface{})
+
+	// StructuredOutput, when set, receives a newline-delimited JSON
+	// event for every plan/apply run lifecycle transition, so a CI
+	// system can consume run progress without scraping CLI output.
+	StructuredOutput io.Writer
+
+	services     *disco.Disco
+	client       *tfe.Client
+	hostname     string
+	organization string
+
+	// workspace is the name of a single workspace to map to, set when
+	// the `workspaces` block configures `name`. prefix and tags are the
+	// alternative, mutually exclusive ways to select multiple
+	// workspaces.
+	workspace string
+	prefix    string
+	tags      []string
+
+	// encryption holds the parsed `encryption` block, or nil if state
+	// encryption isn't configured for this backend instance.
+	encryption *encryptionConfig
+
+	// local is used to run operations locally (plan -local-execution-mode
+	// style flows and `terraform state` subcommands) against state
+	// managed remotely.
+	local backend.Enhanced
+
+	schema *configschema.Block
+}
+
+var _ backend.Backend = (*Remote)(nil)
+
+// New creates a new initialized remote backend.
+func New(services *disco.Disco) *Remote {
+	return &Remote{services: services}
+}
+
+// ConfigSchema implements backend.Backend.
+func (b *Remote) ConfigSchema() *configschema.Block {
+	if b.schema != nil {
+		return b.schema
+	}
+
+	b.schema = &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"hostname": {
+				Type:     cty.String,
+				Optional: true,
+			},
+			"organization": {
+				Type:     cty.String,
+				Required: true,
+			},
+			"token": {
+				Type:     cty.String,
+				Optional: true,
+			},
+		},
+		BlockTypes: map[string]*configschema.NestedBlock{
+			"workspaces": {
+				Nesting: configschema.NestingSingle,
+				Block: configschema.Block{
+					Attributes: map[string]*configschema.Attribute{
+						"name": {
+							Type:     cty.String,
+							Optional: true,
+						},
+						"prefix": {
+							Type:     cty.String,
+							Optional: true,
+						},
+						"tags": {
+							Type:     cty.Set(cty.String),
+							Optional: true,
+						},
+					},
+				},
+			},
+			"encryption": {
+				Nesting: configschema.NestingSingle,
+				Block: configschema.Block{
+					Attributes: map[string]*configschema.Attribute{
+						"method": {
+							Type:     cty.String,
+							Optional: true,
+						},
+						"passphrase": {
+							Type:      cty.String,
+							Optional:  true,
+							Sensitive: true,
+						},
+						"kms_key_id": {
+							Type:     cty.String,
+							Optional: true,
+						},
+						"fallback": {
+							Type:     cty.Bool,
+							Optional: true,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return b.schema
+}
+
+// ValidateConfig implements backend.Backend.
+func (b *Remote) ValidateConfig(obj cty.Value) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+
+	if val := obj.GetAttr("organization"); val.IsNull() || val.AsString() == "" {
+		diags = diags.Append(tfdiags.AttributeValue(
+			tfdiags.Error,
+			"Invalid organization value",
+			`The "organization" attribute value must not be empty.`,
+			cty.Path{cty.GetAttrStep{Name: "organization"}},
+		))
+	}
+
+	if workspacesVal := obj.GetAttr("workspaces"); !workspacesVal.IsNull() {
+		var name, prefix string
+		if v := workspacesVal.GetAttr("name"); !v.IsNull() {
+			name = v.AsString()
+		}
+		if v := workspacesVal.GetAttr("prefix"); !v.IsNull() {
+			prefix = v.AsString()
+		}
+		tags := parseWorkspaceTags(workspacesVal)
+
+		diags = diags.Append(validateWorkspaceMapping(name, prefix, tags))
+
+		if name == "" && prefix == "" && len(tags) == 0 {
+			diags = diags.Append(tfdiags.AttributeValue(
+				tfdiags.Error,
+				"Invalid workspaces configuration",
+				`Either "name", "prefix" or "tags" must be set in the "workspaces" block.`,
+				cty.Path{cty.GetAttrStep{Name: "workspaces"}},
+			))
+		}
+	}
+
+	_, encDiags := parseEncryptionConfig(obj)
+	diags = diags.Append(encDiags)
+
+	return diags
+}
+
+// Configure implements backend.Backend.
+func (b *Remote) Configure(obj cty.Value) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+
+	b.hostname = defaultHostname
+	if val := obj.GetAttr("hostname"); !val.IsNull() {
+		b.hostname = val.AsString()
+	}
+
+	b.organization = obj.GetAttr("organization").AsString()
+
+	workspacesVal := obj.GetAttr("workspaces")
+	if v := workspacesVal.GetAttr("name"); !v.IsNull() {
+		b.workspace = v.AsString()
+	}
+	if v := workspacesVal.GetAttr("prefix"); !v.IsNull() {
+		b.prefix = v.AsString()
+	}
+	b.tags = parseWorkspaceTags(workspacesVal)
+
+	encConfig, encDiags := parseEncryptionConfig(obj)
+	diags = diags.Append(encDiags)
+	if encDiags.HasErrors() {
+		return diags
+	}
+	b.encryption = encConfig
+
+	var token string
+	if val := obj.GetAttr("token"); !val.IsNull() {
+		token = val.AsString()
+	}
+
+	host := svchost.Hostname(b.hostname)
+	service, err := b.services.DiscoverServiceURL(host, "tfe.v2")
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to request discovery document",
+			fmt.Sprintf("Terraform failed to request the discovery document from %q: %s.", b.hostname, err),
+		))
+		return diags
+	}
+
+	cfg := &tfe.Config{
+		Address: service.String(),
+		Token:   token,
+	}
+
+	client, err := tfe.NewClient(cfg)
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to create the Terraform Cloud/Enterprise client",
+			fmt.Sprintf("The %q backend encountered an unexpected error while creating the client: %s", "remote", err),
+		))
+		return diags
+	}
+	b.client = client
+
+	return diags
+}
+
+// Workspaces implements backend.Backend.
+func (b *Remote) Workspaces() ([]string, error) {
+	if b.workspace != "" {
+		return []string{backend.DefaultStateName}, nil
+	}
+
+	ctx := context.Background()
+
+	if len(b.tags) > 0 {
+		names, err := b.workspacesByTags(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not list workspaces by tags: %w", err)
+		}
+		return names, nil
+	}
+
+	options := tfe.WorkspaceListOptions{}
+	if b.prefix != "" {
+		options.Search = tfe.String(b.prefix)
+	}
+
+	var names []string
+	for {
+		wl, err := b.client.Workspaces.List(ctx, b.organization, options)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range wl.Items {
+			if b.prefix == "" || !isPrefixedName(w.Name, b.prefix) {
+				continue
+			}
+			names = append(names, w.Name)
+		}
+
+		if wl.CurrentPage >= wl.TotalPages {
+			break
+		}
+		options.PageNumber = wl.NextPage
+	}
+
+	return names, nil
+}
+
+func isPrefixedName(name, prefix string) bool {
+	return len(name) > len(prefix) && name[:len(prefix)] == prefix
+}
+
+// DeleteWorkspace implements backend.Backend.
+func (b *Remote) DeleteWorkspace(name string) error {
+	if name == backend.DefaultStateName {
+		name = b.workspace
+	}
+
+	ctx := context.Background()
+
+	ws, err := b.client.Workspaces.Read(ctx, b.organization, name)
+	if err != nil {
+		if err == tfe.ErrResourceNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if err := b.checkDeleteAllowedByTags(ws); err != nil {
+		return err
+	}
+
+	return b.client.Workspaces.Delete(ctx, b.organization, name)
+}
+
+// StateMgr implements backend.Backend.
+func (b *Remote) StateMgr(name string) (statemgr.Full, error) {
+	if name == backend.DefaultStateName {
+		name = b.workspace
+	}
+
+	ctx := context.Background()
+
+	ws, err := b.client.Workspaces.Read(ctx, b.organization, name)
+	if err != nil {
+		if err != tfe.ErrResourceNotFound {
+			return nil, fmt.Errorf("could not read workspace %q: %w", name, err)
+		}
+
+		options := tfe.WorkspaceCreateOptions{Name: tfe.String(name)}
+		if len(b.tags) > 0 {
+			for _, t := range b.tags {
+				options.Tags = append(options.Tags, &tfe.Tag{Name: t})
+			}
+		}
+
+		ws, err = b.client.Workspaces.Create(ctx, b.organization, options)
+		if err != nil {
+			return nil, fmt.Errorf("could not create workspace %q: %w", name, err)
+		}
+	}
+
+	if err := b.ensureTags(ctx, ws); err != nil {
+		return nil, fmt.Errorf("could not update tags on workspace %q: %w", name, err)
+	}
+
+	client := newEncryptedClient(&remoteClient{
+		client:       b.client,
+		organization: b.organization,
+		workspace:    ws,
+	}, b.encryption)
+
+	return &remote.State{Client: client}, nil
+}
@@ -0,0 +1,224 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/terraform/backend"
+)
+
+// backoffDuration is the delay between run status polls.
+const backoffDuration = 2 * time.Second
+
+// Operation implements backend.Enhanced, creating a TFE run for the
+// requested plan/apply operation. It writes one line to b.CLI once the
+// run is queued and, when configured, streams the run's progress as
+// structured JSON events on b.StructuredOutput.
+func (b *Remote) Operation(ctx context.Context, op *backend.Operation) (*backend.RunningOperation, error) {
+	switch op.Type {
+	case backend.OperationTypePlan, backend.OperationTypeApply, backend.OperationTypeDestroy:
+	default:
+		return nil, fmt.Errorf("remote backend does not support %q operations", op.Type)
+	}
+
+	w := b.newStructuredOutputWriter()
+
+	name := op.Workspace
+	if name == backend.DefaultStateName {
+		name = b.workspace
+	}
+
+	ws, err := b.client.Workspaces.Read(ctx, b.organization, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read workspace %q: %w", name, err)
+	}
+
+	cv, err := b.client.ConfigurationVersions.Create(ctx, ws.ID, tfe.ConfigurationVersionCreateOptions{
+		AutoQueueRuns: tfe.Bool(false),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create configuration version: %w", err)
+	}
+
+	r, err := b.client.Runs.Create(ctx, tfe.RunCreateOptions{
+		IsDestroy:            tfe.Bool(op.Type == backend.OperationTypeDestroy),
+		Message:              tfe.String("Queued by structured remote operation"),
+		ConfigurationVersion: cv,
+		Workspace:            ws,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create run: %w", err)
+	}
+
+	w.runQueued(r, ws.Name)
+	if b.CLI != nil {
+		b.CLI.Output(fmt.Sprintf("Waiting for the plan to start for run %s...", r.ID))
+	}
+
+	// runningCtx is independent of ctx (which the caller may cancel once
+	// it's done waiting) and is only ever canceled by the goroutine below
+	// once waitForRun returns (the run reached a terminal state, a poll
+	// failed, or ctx was canceled), so embedding it as the
+	// RunningOperation's Context gives callers a real completion signal
+	// to block on via runningOp.Done().
+	runningCtx, done := context.WithCancel(context.Background())
+	runningOp := &backend.RunningOperation{
+		Context: runningCtx,
+	}
+
+	go func() {
+		defer done()
+		b.waitForRun(ctx, op, r, ws, w, runningOp)
+	}()
+
+	return runningOp, nil
+}
+
+// waitForRun polls the run until it reaches a terminal state, emitting a
+// structured event at each lifecycle transition. It writes nothing to
+// b.CLI; only a failed poll is reported, through b.ShowDiagnostics. Each
+// transition is only reported once, even though the run is re-read on
+// every poll.
+//
+// Run confirmation is gated on op.Type: a plan operation stops as soon as
+// the plan is ready and never drives (or waits on) an apply, and an apply
+// or destroy operation with op.AutoApprove set explicitly confirms the
+// run via Runs.Apply once it's planned, rather than relying on TFE
+// workspace-level auto-apply to decide whether the run proceeds. Without
+// op.AutoApprove, a planned run is not confirmed: a run_errored event is
+// emitted and the operation fails.
+func (b *Remote) waitForRun(ctx context.Context, op *backend.Operation, r *tfe.Run, ws *tfe.Workspace, w *structuredOutputWriter, runningOp *backend.RunningOperation) {
+	w.planStarted(r, ws.Name)
+
+	var sawPlan, sawCostEstimate, sawApplyStarted, confirmed bool
+	seenPolicyChecks := make(map[string]bool)
+
+	for {
+		current, err := b.client.Runs.ReadWithOptions(ctx, r.ID, &tfe.RunReadOptions{
+			Include: "plan,apply,policy-checks,cost-estimate",
+		})
+		if err != nil {
+			w.runErrored(r, ws.Name, err.Error())
+			if b.ShowDiagnostics != nil {
+				b.ShowDiagnostics(err)
+			}
+			runningOp.Result = backend.OperationFailure
+			return
+		}
+
+		if current.Plan != nil && !sawPlan {
+			sawPlan = true
+			b.streamPlanLog(ctx, current.Plan, r, ws, w)
+			// Re-read the plan now that its log is drained, so the
+			// resource change counts are the finished plan's.
+			plan := current.Plan
+			if p, err := b.client.Plans.Read(ctx, plan.ID); err == nil {
+				plan = p
+			}
+			w.planFinished(current, ws.Name, plan)
+		}
+		if current.CostEstimate != nil && !sawCostEstimate {
+			sawCostEstimate = true
+			w.costEstimate(current, ws.Name, current.CostEstimate)
+		}
+		for _, pc := range current.PolicyChecks {
+			if !seenPolicyChecks[pc.ID] {
+				seenPolicyChecks[pc.ID] = true
+				w.policyCheck(current, ws.Name, pc)
+			}
+		}
+
+		switch current.Status {
+		case tfe.RunPlanned:
+			if op.Type == backend.OperationTypePlan {
+				// A plan operation is done once the plan itself is
+				// ready; it must never drive or observe an apply.
+				runningOp.Result = backend.OperationSuccess
+				return
+			}
+			if !confirmed {
+				if !op.AutoApprove {
+					w.runErrored(current, ws.Name, "run is awaiting confirmation; re-run with -auto-approve")
+					runningOp.Result = backend.OperationFailure
+					return
+				}
+				if _, err := b.client.Runs.Apply(ctx, current.ID, tfe.RunApplyOptions{}); err != nil {
+					w.runErrored(current, ws.Name, fmt.Sprintf("could not confirm run: %s", err))
+					runningOp.Result = backend.OperationFailure
+					return
+				}
+				confirmed = true
+			}
+		case tfe.RunApplying:
+			if !sawApplyStarted {
+				sawApplyStarted = true
+				w.applyStarted(current, ws.Name)
+			}
+		case tfe.RunApplied:
+			b.streamApplyLog(ctx, current.Apply, r, ws, w)
+			w.applyFinished(current, ws.Name, current.Apply)
+			runningOp.Result = backend.OperationSuccess
+			return
+		case tfe.RunErrored, tfe.RunCanceled, tfe.RunDiscarded:
+			w.runErrored(current, ws.Name, fmt.Sprintf("run ended with status %q", current.Status))
+			runningOp.Result = backend.OperationFailure
+			return
+		case tfe.RunPlannedAndFinished:
+			runningOp.Result = backend.OperationSuccess
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			w.runErrored(current, ws.Name, ctx.Err().Error())
+			runningOp.Result = backend.OperationFailure
+			return
+		case <-time.After(backoffDuration):
+		}
+	}
+}
+
+// streamPlanLog reads the run's plan log from TFE and emits every chunk
+// it reads as its own plan_log event, until the log ends or a read fails.
+func (b *Remote) streamPlanLog(ctx context.Context, plan *tfe.Plan, r *tfe.Run, ws *tfe.Workspace, w *structuredOutputWriter) {
+	logReader, err := b.client.Plans.Logs(ctx, plan.ID)
+	if err != nil {
+		return
+	}
+	defer logReader.Close()
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := logReader.Read(buf)
+		if n > 0 {
+			w.planLog(r, ws.Name, buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// streamApplyLog reads the run's apply log in full and emits it as a
+// single apply_log event.
+func (b *Remote) streamApplyLog(ctx context.Context, apply *tfe.Apply, r *tfe.Run, ws *tfe.Workspace, w *structuredOutputWriter) {
+	if apply == nil {
+		return
+	}
+
+	logReader, err := b.client.Applies.Logs(ctx, apply.ID)
+	if err != nil {
+		return
+	}
+	defer logReader.Close()
+
+	chunk, err := ioutil.ReadAll(logReader)
+	if err != nil || len(chunk) == 0 {
+		return
+	}
+
+	w.applyLog(r, ws.Name, chunk)
+}
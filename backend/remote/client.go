@@ -0,0 +1,69 @@
+package remote
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/terraform/state/remote"
+)
+
+// remoteClient implements remote.Client backed by a TFE workspace's
+// state versions API. It's the innermost client returned from
+// Remote.StateMgr, before any client-side encryption is layered on top
+// by newEncryptedClient.
+type remoteClient struct {
+	client       *tfe.Client
+	organization string
+	workspace    *tfe.Workspace
+}
+
+var _ remote.Client = (*remoteClient)(nil)
+
+// Get implements remote.Client.
+func (c *remoteClient) Get() (*remote.Payload, error) {
+	ctx := context.Background()
+
+	sv, err := c.client.StateVersions.ReadCurrent(ctx, c.workspace.ID)
+	if err != nil {
+		if err == tfe.ErrResourceNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read current state version: %w", err)
+	}
+
+	data, err := c.client.StateVersions.Download(ctx, sv.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not download state: %w", err)
+	}
+
+	sum := md5.Sum(data)
+	return &remote.Payload{
+		Data: data,
+		MD5:  sum[:],
+	}, nil
+}
+
+// Put implements remote.Client.
+func (c *remoteClient) Put(data []byte) error {
+	ctx := context.Background()
+
+	sum := md5.Sum(data)
+
+	_, err := c.client.StateVersions.Create(ctx, c.workspace.ID, tfe.StateVersionCreateOptions{
+		MD5:   tfe.String(fmt.Sprintf("%x", sum)),
+		State: tfe.String(base64.StdEncoding.EncodeToString(data)),
+	})
+	if err != nil {
+		return fmt.Errorf("could not create state version: %w", err)
+	}
+
+	return nil
+}
+
+// Delete implements remote.Client.
+func (c *remoteClient) Delete() error {
+	return nil
+}
@@ -0,0 +1,304 @@
+package remote
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform/state/remote"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// encryptionMethod identifies the scheme used to protect a DEK (data
+// encryption key) in a stateEnvelope. New methods may be added without
+// breaking the envelope format, since the method is recorded alongside
+// the wrapped key.
+type encryptionMethod string
+
+const (
+	encryptionMethodNone   encryptionMethod = ""
+	encryptionMethodAESGCM encryptionMethod = "aes_gcm"
+	encryptionMethodKMS    encryptionMethod = "kms"
+)
+
+// pbkdf2Iterations is intentionally conservative; it's re-derived on every
+// Get and Put, so an expensive KDF slows down every state operation.
+const pbkdf2Iterations = 200000
+
+// stateEnvelope is the on-disk (or on-TFE) representation of an encrypted
+// state. It is marshaled to JSON and stored as the opaque blob that TFE
+// otherwise treats as a plain state upload.
+type stateEnvelope struct {
+	Method     encryptionMethod `json:"method"`
+	KeyID      string           `json:"key_id,omitempty"`
+	WrappedDEK []byte           `json:"wrapped_dek,omitempty"`
+	Nonce      []byte           `json:"nonce"`
+	Ciphertext []byte           `json:"ciphertext"`
+}
+
+// encryptionConfig is the parsed and validated form of the `encryption`
+// block in the backend configuration.
+type encryptionConfig struct {
+	method     encryptionMethod
+	passphrase string
+	kmsKeyID   string
+
+	// fallback allows reading unencrypted state that predates turning
+	// encryption on for a workspace. Writes are always encrypted
+	// regardless of this setting.
+	fallback bool
+}
+
+// keyWrapper wraps and unwraps the per-state DEK for a given encryption
+// method. aesGCMKeyWrapper derives the DEK directly from a passphrase;
+// a KMS-backed wrapper instead generates a random DEK and asks the KMS
+// service to encrypt/decrypt it.
+type keyWrapper interface {
+	// wrap returns the key material to store in the envelope's
+	// WrappedDEK field, plus the resulting DEK to encrypt with.
+	wrap() (dek []byte, wrapped []byte, keyID string, err error)
+	// unwrap recovers the DEK from a previously wrapped value.
+	unwrap(wrapped []byte, keyID string) (dek []byte, err error)
+}
+
+// aesGCMKeyWrapper derives the DEK from a user-supplied passphrase using
+// PBKDF2-SHA256. The "wrapped" key is simply the salt, since the DEK
+// itself is never stored.
+type aesGCMKeyWrapper struct {
+	passphrase string
+}
+
+func (w *aesGCMKeyWrapper) wrap() ([]byte, []byte, string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	dek := pbkdf2.Key([]byte(w.passphrase), salt, pbkdf2Iterations, 32, sha256.New)
+	return dek, salt, "", nil
+}
+
+func (w *aesGCMKeyWrapper) unwrap(wrapped []byte, keyID string) ([]byte, error) {
+	return pbkdf2.Key([]byte(w.passphrase), wrapped, pbkdf2Iterations, 32, sha256.New), nil
+}
+
+// kmsKeyWrapper wraps a randomly generated DEK using a remote KMS
+// (AWS/GCP/Vault, selected by the key ID's scheme prefix). The actual
+// KMS client is created lazily so that tests can swap it out.
+type kmsKeyWrapper struct {
+	keyID string
+	kms   kmsClient
+}
+
+// kmsClient is the minimal surface the encryption layer needs from a KMS
+// provider. Concrete implementations live in kms.go and are selected by
+// newKMSClient based on the key ID's scheme (e.g. "awskms://...").
+type kmsClient interface {
+	Encrypt(keyID string, plaintext []byte) ([]byte, error)
+	Decrypt(keyID string, ciphertext []byte) ([]byte, error)
+}
+
+func (w *kmsKeyWrapper) wrap() ([]byte, []byte, string, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to generate data encryption key: %w", err)
+	}
+	wrapped, err := w.kms.Encrypt(w.keyID, dek)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to wrap data encryption key: %w", err)
+	}
+	return dek, wrapped, w.keyID, nil
+}
+
+func (w *kmsKeyWrapper) unwrap(wrapped []byte, keyID string) ([]byte, error) {
+	dek, err := w.kms.Decrypt(keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data encryption key: %w", err)
+	}
+	return dek, nil
+}
+
+// encryptedClient wraps a remote.Client, transparently encrypting state
+// on Put and decrypting it on Get. It implements remote.Client so it can
+// be substituted anywhere a *Remote backend hands out a state manager.
+type encryptedClient struct {
+	remote.Client
+
+	config  *encryptionConfig
+	wrapper keyWrapper
+}
+
+// newEncryptedClient selects a keyWrapper for the configured method and
+// wraps client accordingly. It returns the client unmodified if no
+// encryption method is configured.
+func newEncryptedClient(client remote.Client, config *encryptionConfig) remote.Client {
+	if config == nil || config.method == encryptionMethodNone {
+		return client
+	}
+
+	var wrapper keyWrapper
+	switch config.method {
+	case encryptionMethodAESGCM:
+		wrapper = &aesGCMKeyWrapper{passphrase: config.passphrase}
+	case encryptionMethodKMS:
+		wrapper = &kmsKeyWrapper{keyID: config.kmsKeyID, kms: newKMSClient(config.kmsKeyID)}
+	}
+
+	return &encryptedClient{
+		Client:  client,
+		config:  config,
+		wrapper: wrapper,
+	}
+}
+
+func (c *encryptedClient) Get() (*remote.Payload, error) {
+	payload, err := c.Client.Get()
+	if err != nil || payload == nil {
+		return payload, err
+	}
+
+	var envelope stateEnvelope
+	if err := json.Unmarshal(payload.Data, &envelope); err != nil || envelope.Method == encryptionMethodNone {
+		if c.config.fallback {
+			// Not an envelope (or an unencrypted one): treat the bytes
+			// as plaintext state, as written before encryption was
+			// enabled for this workspace.
+			return payload, nil
+		}
+		return nil, fmt.Errorf("state is not encrypted and encryption.fallback is not enabled")
+	}
+
+	dek, err := c.wrapper.unwrap(envelope.WrappedDEK, envelope.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt state: %w", err)
+	}
+
+	plaintext, err := decryptAESGCM(dek, envelope.Nonce, envelope.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt state: %w", err)
+	}
+
+	payload.Data = plaintext
+	return payload, nil
+}
+
+func (c *encryptedClient) Put(data []byte) error {
+	dek, wrapped, keyID, err := c.wrapper.wrap()
+	if err != nil {
+		return fmt.Errorf("failed to encrypt state: %w", err)
+	}
+
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to encrypt state: %w", err)
+	}
+
+	ciphertext, err := encryptAESGCM(dek, nonce, data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt state: %w", err)
+	}
+
+	envelope := stateEnvelope{
+		Method:     c.config.method,
+		KeyID:      keyID,
+		WrappedDEK: wrapped,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	}
+
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt state: %w", err)
+	}
+
+	return c.Client.Put(raw)
+}
+
+func encryptAESGCM(key, nonce, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+func decryptAESGCM(key, nonce, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// parseEncryptionConfig reads the `encryption` block out of the backend
+// config object, returning a nil config (encryption disabled) when the
+// block is absent. It's called from Remote.Configure alongside the rest
+// of the block parsing.
+func parseEncryptionConfig(obj cty.Value) (*encryptionConfig, tfdiags.Diagnostics) {
+	var diags tfdiags.Diagnostics
+
+	encVal := obj.GetAttr("encryption")
+	if encVal.IsNull() {
+		return nil, diags
+	}
+
+	config := &encryptionConfig{fallback: false}
+
+	if v := encVal.GetAttr("method"); !v.IsNull() {
+		config.method = encryptionMethod(v.AsString())
+	}
+	if v := encVal.GetAttr("passphrase"); !v.IsNull() {
+		config.passphrase = v.AsString()
+	}
+	if v := encVal.GetAttr("kms_key_id"); !v.IsNull() {
+		config.kmsKeyID = v.AsString()
+	}
+	if v := encVal.GetAttr("fallback"); !v.IsNull() {
+		config.fallback = v.True()
+	}
+
+	switch config.method {
+	case encryptionMethodAESGCM:
+		if config.passphrase == "" {
+			diags = diags.Append(tfdiags.AttributeValue(
+				tfdiags.Error,
+				"Missing required argument",
+				`The "aes_gcm" encryption method requires "passphrase" to be set.`,
+				cty.Path{cty.GetAttrStep{Name: "encryption"}, cty.GetAttrStep{Name: "passphrase"}},
+			))
+		}
+	case encryptionMethodKMS:
+		if config.kmsKeyID == "" {
+			diags = diags.Append(tfdiags.AttributeValue(
+				tfdiags.Error,
+				"Missing required argument",
+				`The "kms" encryption method requires "kms_key_id" to be set.`,
+				cty.Path{cty.GetAttrStep{Name: "encryption"}, cty.GetAttrStep{Name: "kms_key_id"}},
+			))
+		}
+	case encryptionMethodNone:
+		// No method selected; the block is present but inert. This lets
+		// users stage the block before choosing a method.
+	default:
+		diags = diags.Append(tfdiags.AttributeValue(
+			tfdiags.Error,
+			"Invalid encryption method",
+			fmt.Sprintf(`"%s" is not a supported encryption method; use "aes_gcm" or "kms".`, config.method),
+			cty.Path{cty.GetAttrStep{Name: "encryption"}, cty.GetAttrStep{Name: "method"}},
+		))
+	}
+
+	return config, diags
+}
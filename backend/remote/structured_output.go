@@ -0,0 +1,199 @@
+package remote
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+// structuredEventType enumerates the run lifecycle transitions emitted to
+// b.StructuredOutput, in the order a run normally passes through them.
+type structuredEventType string
+
+const (
+	eventRunQueued     structuredEventType = "run_queued"
+	eventPlanStarted   structuredEventType = "plan_started"
+	eventPlanLog       structuredEventType = "plan_log"
+	eventPlanFinished  structuredEventType = "plan_finished"
+	eventCostEstimate  structuredEventType = "cost_estimate"
+	eventPolicyCheck   structuredEventType = "policy_check"
+	eventApplyStarted  structuredEventType = "apply_started"
+	eventApplyLog      structuredEventType = "apply_log"
+	eventApplyFinished structuredEventType = "apply_finished"
+	eventRunErrored    structuredEventType = "run_errored"
+)
+
+// resourceChangeCounts summarizes a plan's resource changes, parsed out
+// of the TFE plan resource so CI consumers don't have to scrape the
+// human-oriented plan log for the "Plan: N to add..." line.
+type resourceChangeCounts struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+	Import  int `json:"import"`
+}
+
+// structuredEvent is a single newline-delimited JSON record written to
+// b.StructuredOutput. Raw carries the underlying TFE API object for the
+// event, where one is available, so consumers that want more than the
+// summarized fields can still get at it without a second API call.
+type structuredEvent struct {
+	Type      structuredEventType   `json:"type"`
+	Timestamp time.Time             `json:"timestamp"`
+	RunID     string                `json:"run_id"`
+	Workspace string                `json:"workspace"`
+	Changes   *resourceChangeCounts `json:"changes,omitempty"`
+	Message   string                `json:"message,omitempty"`
+	Raw       interface{}           `json:"raw,omitempty"`
+}
+
+// structuredOutputWriter serializes structuredEvents as newline-delimited
+// JSON onto b.StructuredOutput. Writes are serialized with a mutex since
+// plan and apply log chunks can arrive while other lifecycle events are
+// being emitted from a different goroutine.
+type structuredOutputWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (b *Remote) newStructuredOutputWriter() *structuredOutputWriter {
+	if b.StructuredOutput == nil {
+		return nil
+	}
+	return &structuredOutputWriter{out: b.StructuredOutput}
+}
+
+func (w *structuredOutputWriter) emit(e structuredEvent) {
+	if w == nil {
+		return
+	}
+
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.out.Write(append(raw, '\n'))
+}
+
+func (w *structuredOutputWriter) runQueued(run *tfe.Run, workspace string) {
+	w.emit(structuredEvent{
+		Type:      eventRunQueued,
+		Timestamp: run.CreatedAt,
+		RunID:     run.ID,
+		Workspace: workspace,
+		Raw:       run,
+	})
+}
+
+func (w *structuredOutputWriter) planStarted(run *tfe.Run, workspace string) {
+	w.emit(structuredEvent{
+		Type:      eventPlanStarted,
+		Timestamp: run.CreatedAt,
+		RunID:     run.ID,
+		Workspace: workspace,
+	})
+}
+
+// planLog emits a single chunk of the raw plan log as it's read from
+// TFE, so consumers can stream progress without waiting for the whole
+// log to finish.
+func (w *structuredOutputWriter) planLog(run *tfe.Run, workspace string, chunk []byte) {
+	w.emit(structuredEvent{
+		Type:      eventPlanLog,
+		Timestamp: time.Now(),
+		RunID:     run.ID,
+		Workspace: workspace,
+		Message:   string(chunk),
+	})
+}
+
+func (w *structuredOutputWriter) planFinished(run *tfe.Run, workspace string, plan *tfe.Plan) {
+	w.emit(structuredEvent{
+		Type:      eventPlanFinished,
+		Timestamp: time.Now(),
+		RunID:     run.ID,
+		Workspace: workspace,
+		Changes:   resourceChangesFromPlan(plan),
+		Raw:       plan,
+	})
+}
+
+func (w *structuredOutputWriter) policyCheck(run *tfe.Run, workspace string, check *tfe.PolicyCheck) {
+	w.emit(structuredEvent{
+		Type:      eventPolicyCheck,
+		Timestamp: time.Now(),
+		RunID:     run.ID,
+		Workspace: workspace,
+		Raw:       check,
+	})
+}
+
+func (w *structuredOutputWriter) costEstimate(run *tfe.Run, workspace string, estimate *tfe.CostEstimate) {
+	w.emit(structuredEvent{
+		Type:      eventCostEstimate,
+		Timestamp: time.Now(),
+		RunID:     run.ID,
+		Workspace: workspace,
+		Raw:       estimate,
+	})
+}
+
+func (w *structuredOutputWriter) applyStarted(run *tfe.Run, workspace string) {
+	w.emit(structuredEvent{
+		Type:      eventApplyStarted,
+		Timestamp: time.Now(),
+		RunID:     run.ID,
+		Workspace: workspace,
+	})
+}
+
+func (w *structuredOutputWriter) applyLog(run *tfe.Run, workspace string, chunk []byte) {
+	w.emit(structuredEvent{
+		Type:      eventApplyLog,
+		Timestamp: time.Now(),
+		RunID:     run.ID,
+		Workspace: workspace,
+		Message:   string(chunk),
+	})
+}
+
+func (w *structuredOutputWriter) applyFinished(run *tfe.Run, workspace string, apply *tfe.Apply) {
+	w.emit(structuredEvent{
+		Type:      eventApplyFinished,
+		Timestamp: time.Now(),
+		RunID:     run.ID,
+		Workspace: workspace,
+		Raw:       apply,
+	})
+}
+
+func (w *structuredOutputWriter) runErrored(run *tfe.Run, workspace string, message string) {
+	w.emit(structuredEvent{
+		Type:      eventRunErrored,
+		Timestamp: time.Now(),
+		RunID:     run.ID,
+		Workspace: workspace,
+		Message:   message,
+		Raw:       run,
+	})
+}
+
+// resourceChangesFromPlan parses the add/change/destroy/import counts
+// off a TFE plan resource for the plan_finished event.
+func resourceChangesFromPlan(plan *tfe.Plan) *resourceChangeCounts {
+	if plan == nil {
+		return nil
+	}
+	return &resourceChangeCounts{
+		Add:     plan.ResourceAdditions,
+		Change:  plan.ResourceChanges,
+		Destroy: plan.ResourceDestructions,
+		Import:  plan.ResourceImports,
+	}
+}
@@ -0,0 +1,58 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/backend"
+)
+
+func TestRemote_structuredOutputEventStream(t *testing.T) {
+	b, w := testBackendStructuredOutput(t)
+
+	op := &backend.Operation{
+		Type:      backend.OperationTypePlan,
+		Workspace: backend.DefaultStateName,
+	}
+
+	runningOp, err := b.Operation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if runningOp == nil {
+		t.Fatal("expected a non-nil running operation")
+	}
+
+	select {
+	case <-runningOp.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the operation to complete")
+	}
+
+	if runningOp.Result != backend.OperationSuccess {
+		t.Fatalf("expected the plan operation to succeed, got result %v", runningOp.Result)
+	}
+
+	lines := w.lines()
+	if len(lines) == 0 {
+		t.Fatal("expected at least one structured event to have been emitted")
+	}
+
+	var first struct {
+		Type      structuredEventType `json:"type"`
+		RunID     string              `json:"run_id"`
+		Workspace string              `json:"workspace"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("event is not valid JSON: %v", err)
+	}
+	if first.Type != eventRunQueued {
+		t.Fatalf("expected first event to be %q, got %q", eventRunQueued, first.Type)
+	}
+	if first.RunID == "" {
+		t.Fatal("expected run_queued event to carry a run_id")
+	}
+}
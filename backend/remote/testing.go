@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	tfe "github.com/hashicorp/go-tfe"
@@ -36,6 +38,16 @@ var (
 	})
 )
 
+// encryptionObjType is the implied cty type of the `encryption` block, so
+// test helpers that don't configure encryption can still supply a
+// properly-typed null value for it.
+var encryptionObjType = cty.Object(map[string]cty.Type{
+	"method":     cty.String,
+	"passphrase": cty.String,
+	"kms_key_id": cty.String,
+	"fallback":   cty.Bool,
+})
+
 func testInput(t *testing.T, answers map[string]string) *mockInput {
 	return &mockInput{answers: answers}
 }
@@ -48,7 +60,31 @@ func testBackendDefault(t *testing.T) *Remote {
 		"workspaces": cty.ObjectVal(map[string]cty.Value{
 			"name":   cty.StringVal("prod"),
 			"prefix": cty.NullVal(cty.String),
+			"tags":   cty.NullVal(cty.Set(cty.String)),
 		}),
+		"encryption": cty.NullVal(encryptionObjType),
+	})
+	return testBackend(t, obj)
+}
+
+// testBackendTags returns a backend configured to select workspaces by
+// tag set rather than by name or prefix.
+func testBackendTags(t *testing.T, tags ...string) *Remote {
+	tagVals := make([]cty.Value, len(tags))
+	for i, tag := range tags {
+		tagVals[i] = cty.StringVal(tag)
+	}
+
+	obj := cty.ObjectVal(map[string]cty.Value{
+		"hostname":     cty.NullVal(cty.String),
+		"organization": cty.StringVal("hashicorp"),
+		"token":        cty.NullVal(cty.String),
+		"workspaces": cty.ObjectVal(map[string]cty.Value{
+			"name":   cty.NullVal(cty.String),
+			"prefix": cty.NullVal(cty.String),
+			"tags":   cty.SetVal(tagVals),
+		}),
+		"encryption": cty.NullVal(encryptionObjType),
 	})
 	return testBackend(t, obj)
 }
@@ -61,11 +97,76 @@ func testBackendNoDefault(t *testing.T) *Remote {
 		"workspaces": cty.ObjectVal(map[string]cty.Value{
 			"name":   cty.NullVal(cty.String),
 			"prefix": cty.StringVal("my-app-"),
+			"tags":   cty.NullVal(cty.Set(cty.String)),
+		}),
+		"encryption": cty.NullVal(encryptionObjType),
+	})
+	return testBackend(t, obj)
+}
+
+// testBackendEncrypted returns a backend configured with client-side
+// state encryption enabled, using the "aes_gcm" method so tests don't
+// depend on a real KMS provider. It's used both to exercise a plain
+// encrypted round-trip and, with fallback set, mixed legacy/encrypted
+// reads against a workspace that already has unencrypted state.
+func testBackendEncrypted(t *testing.T) *Remote {
+	obj := cty.ObjectVal(map[string]cty.Value{
+		"hostname":     cty.NullVal(cty.String),
+		"organization": cty.StringVal("hashicorp"),
+		"token":        cty.NullVal(cty.String),
+		"workspaces": cty.ObjectVal(map[string]cty.Value{
+			"name":   cty.StringVal("prod"),
+			"prefix": cty.NullVal(cty.String),
+			"tags":   cty.NullVal(cty.Set(cty.String)),
+		}),
+		"encryption": cty.ObjectVal(map[string]cty.Value{
+			"method":     cty.StringVal(string(encryptionMethodAESGCM)),
+			"passphrase": cty.StringVal("test-passphrase"),
+			"kms_key_id": cty.NullVal(cty.String),
+			"fallback":   cty.BoolVal(true),
 		}),
 	})
 	return testBackend(t, obj)
 }
 
+// capturingWriter is an io.Writer that keeps every Write call's bytes so
+// tests can assert on the structured output event stream after a run.
+type capturingWriter struct {
+	mu     sync.Mutex
+	writes [][]byte
+}
+
+func (w *capturingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	cp := make([]byte, len(p))
+	copy(cp, p)
+	w.writes = append(w.writes, cp)
+	return len(p), nil
+}
+
+// lines returns each newline-delimited JSON event written so far, in
+// order, with the trailing newline stripped.
+func (w *capturingWriter) lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var lines []string
+	for _, write := range w.writes {
+		lines = append(lines, strings.TrimRight(string(write), "\n"))
+	}
+	return lines
+}
+
+// testBackendStructuredOutput returns a backend with b.StructuredOutput
+// wired to a capturingWriter, so tests can assert on the emitted event
+// stream's order and schema.
+func testBackendStructuredOutput(t *testing.T) (*Remote, *capturingWriter) {
+	b := testBackendDefault(t)
+	w := &capturingWriter{}
+	b.StructuredOutput = w
+	return b, w
+}
+
 func testRemoteClient(t *testing.T) remote.Client {
 	b := testBackendDefault(t)
 	raw, err := b.StateMgr(backend.DefaultStateName)
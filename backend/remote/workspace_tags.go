@@ -0,0 +1,139 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// parseWorkspaceTags reads the `tags` attribute of the `workspaces` block,
+// returning nil if it isn't set. It's called from Remote.ValidateConfig,
+// which checks that `name`, `prefix` and `tags` are mutually exclusive,
+// and from Remote.Configure alongside the `name` and `prefix` parsing.
+func parseWorkspaceTags(workspacesVal cty.Value) []string {
+	tagsVal := workspacesVal.GetAttr("tags")
+	if tagsVal.IsNull() {
+		return nil
+	}
+
+	var tags []string
+	for it := tagsVal.ElementIterator(); it.Next(); {
+		_, v := it.Element()
+		if !v.IsNull() {
+			tags = append(tags, v.AsString())
+		}
+	}
+	return tags
+}
+
+// validateWorkspaceMapping checks that at most one of name, prefix and
+// tags was set in the `workspaces` block.
+func validateWorkspaceMapping(name, prefix string, tags []string) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+
+	set := 0
+	if name != "" {
+		set++
+	}
+	if prefix != "" {
+		set++
+	}
+	if len(tags) > 0 {
+		set++
+	}
+
+	if set > 1 {
+		diags = diags.Append(tfdiags.AttributeValue(
+			tfdiags.Error,
+			"Invalid workspaces configuration",
+			`Only one of "name", "prefix" or "tags" is allowed in the "workspaces" block.`,
+			cty.Path{cty.GetAttrStep{Name: "workspaces"}},
+		))
+	}
+
+	return diags
+}
+
+// workspacesByTags lists the TFE workspaces in b.organization that carry
+// every tag in b.tags. It's used by Workspaces() when the backend was
+// configured with a `tags` set rather than a `name` or `prefix`.
+func (b *Remote) workspacesByTags(ctx context.Context) ([]string, error) {
+	options := tfe.WorkspaceListOptions{
+		Tags: tfe.String(strings.Join(b.tags, ",")),
+	}
+
+	var names []string
+	for {
+		wl, err := b.client.Workspaces.List(ctx, b.organization, options)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range wl.Items {
+			names = append(names, w.Name)
+		}
+
+		if wl.CurrentPage >= wl.TotalPages {
+			break
+		}
+		options.PageNumber = wl.NextPage
+	}
+
+	return names, nil
+}
+
+// hasAllTags reports whether ws carries every tag configured on b.tags.
+func (b *Remote) hasAllTags(ws *tfe.Workspace) bool {
+	have := make(map[string]bool, len(ws.TagNames))
+	for _, t := range ws.TagNames {
+		have[t] = true
+	}
+	for _, t := range b.tags {
+		if !have[t] {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureTags verifies that the given workspace carries all of b.tags,
+// adding any that are missing. It's called from StateMgr right after
+// creating or looking up the named workspace, so a workspace selected by
+// `tags` always stays consistent with that tag set even if it was
+// created out-of-band.
+func (b *Remote) ensureTags(ctx context.Context, ws *tfe.Workspace) error {
+	if len(b.tags) == 0 || b.hasAllTags(ws) {
+		return nil
+	}
+
+	have := make(map[string]bool, len(ws.TagNames))
+	for _, t := range ws.TagNames {
+		have[t] = true
+	}
+
+	var add []*tfe.Tag
+	for _, t := range b.tags {
+		if !have[t] {
+			add = append(add, &tfe.Tag{Name: t})
+		}
+	}
+
+	return b.client.Workspaces.AddTags(ctx, ws.ID, tfe.WorkspaceAddTagsOptions{Tags: add})
+}
+
+// checkDeleteAllowedByTags returns an error if the backend is configured
+// with `tags` and ws does not carry every one of them, refusing
+// DeleteWorkspace from touching workspaces outside the configured set.
+func (b *Remote) checkDeleteAllowedByTags(ws *tfe.Workspace) error {
+	if len(b.tags) == 0 {
+		return nil
+	}
+	if !b.hasAllTags(ws) {
+		return fmt.Errorf("workspace %q does not carry all of the configured tags (%s); refusing to delete it", ws.Name, strings.Join(b.tags, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package remote
+
+import (
+	"context"
+	"testing"
+
+	tfe "github.com/hashicorp/go-tfe"
+)
+
+func TestRemote_stateMgrAppliesTagsOnCreate(t *testing.T) {
+	b := testBackendTags(t, "networking", "prod")
+
+	if len(b.tags) != 2 {
+		t.Fatalf("expected 2 configured tags, got %d", len(b.tags))
+	}
+
+	if _, err := b.StateMgr("example"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	ws, err := b.client.Workspaces.Read(context.Background(), b.organization, "example")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if !b.hasAllTags(ws) {
+		t.Fatalf("expected workspace %q to carry all configured tags, got %v", ws.Name, ws.TagNames)
+	}
+}
+
+func TestRemote_workspacesFiltersByTags(t *testing.T) {
+	b := testBackendTags(t, "networking")
+	ctx := context.Background()
+
+	inScope, err := b.client.Workspaces.Create(ctx, b.organization, tfe.WorkspaceCreateOptions{
+		Name: tfe.String("in-scope"),
+		Tags: []*tfe.Tag{{Name: "networking"}},
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if _, err := b.client.Workspaces.Create(ctx, b.organization, tfe.WorkspaceCreateOptions{
+		Name: tfe.String("out-of-scope"),
+	}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	names, err := b.Workspaces()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var found bool
+	for _, name := range names {
+		if name == "out-of-scope" {
+			t.Fatalf("expected Workspaces() to exclude an out-of-scope workspace, got %v", names)
+		}
+		if name == inScope.Name {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected Workspaces() to include %q, got %v", inScope.Name, names)
+	}
+}
+
+func TestRemote_deleteWorkspaceRefusesOutsideTagSet(t *testing.T) {
+	b := testBackendTags(t, "networking")
+	ctx := context.Background()
+
+	// Create a workspace directly through the client, without the
+	// configured tag, to simulate one that falls outside the tag set
+	// DeleteWorkspace is scoped to.
+	_, err := b.client.Workspaces.Create(ctx, b.organization, tfe.WorkspaceCreateOptions{
+		Name: tfe.String("out-of-scope"),
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if err := b.DeleteWorkspace("out-of-scope"); err == nil {
+		t.Fatal("expected DeleteWorkspace to refuse a workspace outside the tag set, got nil error")
+	}
+}